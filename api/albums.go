@@ -0,0 +1,345 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"github.com/danjac/photoshare/api/models"
+	"github.com/danjac/photoshare/api/storage"
+	"github.com/zenazn/goji/web"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	photoStore = storage.NewPhotoStore()
+	albumMgr   = models.NewAlbumManager()
+)
+
+type albumForm struct {
+	Title string `json:"title"`
+}
+
+type addPhotoForm struct {
+	PhotoID int64 `json:"photoId"`
+}
+
+type reorderForm struct {
+	PhotoIDs []int64 `json:"photoIds"`
+}
+
+type createLinkForm struct {
+	Password  string    `json:"password"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	MaxViews  int64     `json:"maxViews"`
+}
+
+func getAlbumByID(c web.C) (*models.Album, error) {
+	albumID, err := strconv.ParseInt(c.URLParams["id"], 10, 0)
+	if err != nil {
+		return nil, nil
+	}
+	return albumMgr.Get(albumID)
+}
+
+func getAlbums(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	pageNum, _ := strconv.ParseInt(r.FormValue("page"), 10, 0)
+	if pageNum == 0 {
+		pageNum = 1
+	}
+	albums, err := albumMgr.ByOwnerID(pageNum, currentUser.ID)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, albums, http.StatusOK)
+}
+
+func createAlbum(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	form := &albumForm{}
+	if err := json.NewDecoder(r.Body).Decode(form); err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	album := &models.Album{Title: form.Title, OwnerID: currentUser.ID}
+	if err := albumMgr.Insert(album); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, album, http.StatusCreated)
+}
+
+func updateAlbum(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	album, err := getAlbumByID(c)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if album == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !album.CanEdit(currentUser) {
+		handleForbidden(w)
+		return
+	}
+	form := &albumForm{}
+	if err := json.NewDecoder(r.Body).Decode(form); err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	album.Title = form.Title
+	if err := albumMgr.Update(album); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, album, http.StatusOK)
+}
+
+func deleteAlbum(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	album, err := getAlbumByID(c)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if album == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !album.CanDelete(currentUser) {
+		handleForbidden(w)
+		return
+	}
+	if err := albumMgr.Delete(album); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, true, http.StatusOK)
+}
+
+func addPhotoToAlbum(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	album, err := getAlbumByID(c)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if album == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !album.CanEdit(currentUser) {
+		handleForbidden(w)
+		return
+	}
+	form := &addPhotoForm{}
+	if err := json.NewDecoder(r.Body).Decode(form); err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	if err := albumMgr.AddPhoto(album, form.PhotoID); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, true, http.StatusOK)
+}
+
+func removePhotoFromAlbum(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	album, err := getAlbumByID(c)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if album == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !album.CanEdit(currentUser) {
+		handleForbidden(w)
+		return
+	}
+	photoID, err := strconv.ParseInt(c.URLParams["photoID"], 10, 0)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := albumMgr.RemovePhoto(album, photoID); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, true, http.StatusOK)
+}
+
+func reorderAlbumPhotos(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	album, err := getAlbumByID(c)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if album == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !album.CanEdit(currentUser) {
+		handleForbidden(w)
+		return
+	}
+	form := &reorderForm{}
+	if err := json.NewDecoder(r.Body).Decode(form); err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	if err := albumMgr.Reorder(album, form.PhotoIDs); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, true, http.StatusOK)
+}
+
+func createAlbumLink(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	album, err := getAlbumByID(c)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if album == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !album.CanEdit(currentUser) {
+		handleForbidden(w)
+		return
+	}
+	form := &createLinkForm{}
+	if err := json.NewDecoder(r.Body).Decode(form); err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	link, err := albumMgr.CreateLink(album, form.Password, form.ExpiresAt, form.MaxViews)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, link, http.StatusCreated)
+}
+
+func downloadAlbumZip(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	album, err := getAlbumByID(c)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if album == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !album.CanEdit(currentUser) {
+		handleForbidden(w)
+		return
+	}
+	detail, err := albumMgr.GetDetail(album.ID, currentUser)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+detail.Title+".zip\"")
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+	for _, photo := range detail.Photos {
+		if err := writePhotoToZip(zw, &photo); err != nil {
+			handleServerError(w, err)
+			return
+		}
+	}
+}
+
+func writePhotoToZip(zw *zip.Writer, photo *models.Photo) error {
+	reader, err := photoStore.Open(photo.Filename)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	entry, err := zw.Create(photo.Filename)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, reader)
+	return err
+}
+
+// viewSharedAlbum serves an album published via a shareable link to non-authenticated visitors.
+func viewSharedAlbum(c web.C, w http.ResponseWriter, r *http.Request) {
+	link, err := albumMgr.GetLinkBySlug(c.URLParams["slug"])
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if link == nil || link.Expired() {
+		http.NotFound(w, r)
+		return
+	}
+	if link.HasPassword() && !link.CheckPassword(r.FormValue("password")) {
+		handleForbidden(w)
+		return
+	}
+	detail, err := albumMgr.GetDetail(link.AlbumID, nil)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if detail == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := albumMgr.RegisterView(link); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, detail, http.StatusOK)
+}
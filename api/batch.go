@@ -0,0 +1,120 @@
+package api
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"github.com/danjac/photoshare/api/models"
+	"github.com/zenazn/goji/web"
+	"net/http"
+)
+
+type batchIDsForm struct {
+	IDs []int64 `json:"ids"`
+}
+
+type batchTagForm struct {
+	IDs  []int64  `json:"ids"`
+	Tags []string `json:"tags"`
+}
+
+type photoMetadata struct {
+	ID        int64    `json:"id"`
+	Title     string   `json:"title"`
+	Tags      []string `json:"tags"`
+	OwnerID   int64    `json:"ownerId"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+func batchDeletePhotos(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	form := &batchIDsForm{}
+	if err := json.NewDecoder(r.Body).Decode(form); err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	if err := photoMgr.BatchDelete(form.IDs, currentUser); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, true, http.StatusOK)
+}
+
+func batchTagPhotos(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	form := &batchTagForm{}
+	if err := json.NewDecoder(r.Body).Decode(form); err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	if err := photoMgr.BatchTag(form.IDs, form.Tags, currentUser); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, true, http.StatusOK)
+}
+
+func batchDownloadPhotos(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	form := &batchIDsForm{}
+	if err := json.NewDecoder(r.Body).Decode(form); err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	photos, err := photoMgr.GetMany(form.IDs)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+
+	var (
+		toDownload []models.Photo
+		metadata   []photoMetadata
+	)
+	for _, photo := range photos {
+		if !photo.CanEdit(currentUser) {
+			continue
+		}
+		toDownload = append(toDownload, photo)
+		metadata = append(metadata, photoMetadata{
+			ID:        photo.ID,
+			Title:     photo.Title,
+			Tags:      photo.Tags,
+			OwnerID:   photo.OwnerID,
+			CreatedAt: photo.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"photos.zip\"")
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	for _, photo := range toDownload {
+		if err := writePhotoToZip(zw, &photo); err != nil {
+			handleServerError(w, err)
+			return
+		}
+	}
+
+	metadataEntry, err := zw.Create("metadata.json")
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if err := json.NewEncoder(metadataEntry).Encode(metadata); err != nil {
+		handleServerError(w, err)
+		return
+	}
+}
@@ -0,0 +1,81 @@
+// Package convert produces JPEG derivatives of RAW and HEIF source files so
+// they can be thumbnailed and served like any other upload.
+package convert
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+)
+
+var rawExts = map[string]bool{
+	".cr2": true,
+	".nef": true,
+	".arw": true,
+	".dng": true,
+	".raf": true,
+	".orf": true,
+	".rw2": true,
+}
+
+var heifExts = map[string]bool{
+	".heic": true,
+	".heif": true,
+}
+
+// Converter produces a JPEG derivative of filename, returning its path.
+type Converter interface {
+	Convert(ctx context.Context, filename, ext string) (string, error)
+}
+
+// Recognized reports whether ext is a RAW or HEIF extension this package
+// knows how to convert.
+func Recognized(ext string) bool {
+	return rawExts[ext] || heifExts[ext]
+}
+
+type converter struct {
+	darktablePath   string
+	heifConvertPath string
+}
+
+// New returns a Converter that shells out to darktable-cli for RAW formats
+// and heif-convert for HEIF, or nil if neither binary is on PATH.
+func New() Converter {
+	darktablePath, _ := exec.LookPath("darktable-cli")
+	heifConvertPath, _ := exec.LookPath("heif-convert")
+	if darktablePath == "" && heifConvertPath == "" {
+		log.Println("convert: neither darktable-cli nor heif-convert found on PATH, RAW/HEIF ingestion disabled")
+		return nil
+	}
+	return &converter{darktablePath: darktablePath, heifConvertPath: heifConvertPath}
+}
+
+func (c *converter) Convert(ctx context.Context, filename, ext string) (string, error) {
+	dest := filename[:len(filename)-len(ext)] + ".jpg"
+
+	switch {
+	case rawExts[ext]:
+		if c.darktablePath == "" {
+			return "", fmt.Errorf("convert: darktable-cli not available, skipping %s", filename)
+		}
+		cmd := exec.CommandContext(ctx, c.darktablePath, filename, dest)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("convert: darktable-cli failed on %s: %v: %s", filename, err, out)
+		}
+	case heifExts[ext]:
+		if c.heifConvertPath == "" {
+			return "", fmt.Errorf("convert: heif-convert not available, skipping %s", filename)
+		}
+		cmd := exec.CommandContext(ctx, c.heifConvertPath, filename, dest)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("convert: heif-convert failed on %s: %v: %s", filename, err, out)
+		}
+	default:
+		return "", fmt.Errorf("convert: unrecognized extension %s", filepath.Ext(filename))
+	}
+
+	return dest, nil
+}
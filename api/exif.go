@@ -0,0 +1,39 @@
+package api
+
+import (
+	"github.com/zenazn/goji/web"
+	"net/http"
+)
+
+type exifResponse struct {
+	TakenAt  interface{} `json:"takenAt"`
+	Camera   string      `json:"camera"`
+	Lens     string      `json:"lens"`
+	ISO      string      `json:"iso"`
+	Shutter  string      `json:"shutter"`
+	Aperture string      `json:"aperture"`
+	Lat      interface{} `json:"lat"`
+	Lng      interface{} `json:"lng"`
+}
+
+func getPhotoExif(c web.C, w http.ResponseWriter, r *http.Request) {
+	photo, err := photoMgr.Get(c.URLParams["id"])
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if photo == nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, &exifResponse{
+		TakenAt:  photo.TakenAt,
+		Camera:   photo.Camera,
+		Lens:     photo.Lens,
+		ISO:      photo.ISO,
+		Shutter:  photo.Shutter,
+		Aperture: photo.Aperture,
+		Lat:      photo.Lat,
+		Lng:      photo.Lng,
+	}, http.StatusOK)
+}
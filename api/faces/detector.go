@@ -0,0 +1,49 @@
+package faces
+
+import (
+	"github.com/Kagami/go-face"
+)
+
+// Detection is a single detected face and its 128-D embedding.
+type Detection struct {
+	X, Y, W, H int64
+	Embedding  [128]float32
+}
+
+// Detector finds faces in a photo file.
+type Detector interface {
+	Detect(filename string) ([]Detection, error)
+}
+
+type dlibDetector struct {
+	rec *face.Recognizer
+}
+
+// NewDetector loads the dlib models from modelsDir and returns a Detector
+// backed by go-face. It returns an error if the models can't be loaded,
+// which callers should treat as "face detection unavailable" rather than fatal.
+func NewDetector(modelsDir string) (Detector, error) {
+	rec, err := face.NewRecognizer(modelsDir)
+	if err != nil {
+		return nil, err
+	}
+	return &dlibDetector{rec: rec}, nil
+}
+
+func (d *dlibDetector) Detect(filename string) ([]Detection, error) {
+	faces, err := d.rec.RecognizeFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	detections := make([]Detection, len(faces))
+	for i, f := range faces {
+		detections[i] = Detection{
+			X:         int64(f.Rectangle.Min.X),
+			Y:         int64(f.Rectangle.Min.Y),
+			W:         int64(f.Rectangle.Dx()),
+			H:         int64(f.Rectangle.Dy()),
+			Embedding: f.Descriptor,
+		}
+	}
+	return detections, nil
+}
@@ -0,0 +1,286 @@
+package faces
+
+import (
+	"encoding/binary"
+	"github.com/danjac/photoshare/api/models"
+	"github.com/danjac/photoshare/api/storage"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	clusterThreshold      = 0.6
+	defaultRateLimit      = time.Second
+	defaultReclusterDelta = 10
+)
+
+// Indexer is a background worker that detects faces in newly-inserted
+// photos and clusters unassigned faces into candidate Subjects. It is safe
+// to enqueue the same photo more than once: process() skips any photo that
+// already has faces stored, so detection results aren't persisted twice.
+type Indexer struct {
+	detector   Detector
+	subjectMgr models.SubjectManager
+	photoMgr   models.PhotoManager
+	rateLimit  time.Duration
+
+	// reclusterDelta is how many new faces must accumulate since the last
+	// recluster before process() triggers another one.
+	reclusterDelta int
+
+	queue chan int64
+
+	mu           sync.Mutex
+	sinceCluster int
+}
+
+// NewIndexer builds an Indexer. detector may be nil, in which case the
+// indexer silently skips detection work rather than failing - this lets the
+// app run without the dlib-backed detector available. reclusterDelta is how
+// many new faces must accumulate before faces are reclustered; if <= 0,
+// defaultReclusterDelta is used.
+func NewIndexer(detector Detector, subjectMgr models.SubjectManager, photoMgr models.PhotoManager, reclusterDelta int) *Indexer {
+	if reclusterDelta <= 0 {
+		reclusterDelta = defaultReclusterDelta
+	}
+	return &Indexer{
+		detector:       detector,
+		subjectMgr:     subjectMgr,
+		photoMgr:       photoMgr,
+		rateLimit:      defaultRateLimit,
+		reclusterDelta: reclusterDelta,
+		queue:          make(chan int64, 256),
+	}
+}
+
+// Enqueue schedules photoID for face detection. It never blocks: if the
+// queue is full, photoID is logged and dropped.
+func (idx *Indexer) Enqueue(photoID int64) {
+	select {
+	case idx.queue <- photoID:
+	default:
+		log.Printf("faces: indexer queue full, dropping photo %d", photoID)
+	}
+}
+
+// Run processes the queue until stop is closed. Intended to be run in its
+// own goroutine.
+func (idx *Indexer) Run(stop <-chan struct{}) {
+	for {
+		select {
+		case photoID := <-idx.queue:
+			if err := idx.process(photoID); err != nil {
+				log.Printf("faces: failed to index photo %d: %v", photoID, err)
+			}
+			time.Sleep(idx.rateLimit)
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (idx *Indexer) process(photoID int64) error {
+	if idx.detector == nil {
+		return nil
+	}
+
+	existing, err := idx.subjectMgr.FacesForPhoto(photoID)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+
+	photo, err := idx.photoMgr.Get(strconv.FormatInt(photoID, 10))
+	if err != nil {
+		return err
+	}
+	if photo == nil {
+		return nil
+	}
+
+	photoStore := storage.NewPhotoStore()
+	path, err := photoStore.Path(photo.Filename)
+	if err != nil {
+		return err
+	}
+
+	detections, err := idx.detector.Detect(path)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range detections {
+		face := &models.Face{
+			PhotoID:   photoID,
+			X:         d.X,
+			Y:         d.Y,
+			W:         d.W,
+			H:         d.H,
+			Embedding: encodeEmbedding(d.Embedding),
+		}
+		if err := idx.subjectMgr.InsertFace(face); err != nil {
+			return err
+		}
+	}
+
+	idx.mu.Lock()
+	idx.sinceCluster += len(detections)
+	shouldCluster := idx.sinceCluster >= idx.reclusterDelta
+	if shouldCluster {
+		idx.sinceCluster = 0
+	}
+	idx.mu.Unlock()
+
+	if shouldCluster {
+		return idx.recluster()
+	}
+	return nil
+}
+
+// recluster matches unassigned faces against existing subjects' centroids
+// first, then groups whatever's left into new candidate subjects by cosine
+// distance. It's idempotent: faces already assigned to a subject are left
+// untouched, and re-running it on the same unassigned set produces the
+// same clusters.
+func (idx *Indexer) recluster() error {
+	faces, err := idx.subjectMgr.UnassignedFaces(500)
+	if err != nil {
+		return err
+	}
+
+	centroids, err := idx.subjectCentroids()
+	if err != nil {
+		return err
+	}
+
+	var unmatched []models.Face
+	for _, f := range faces {
+		vec := decodeEmbedding(f.Embedding)
+		var bestSubjectID int64
+		bestDist := math.MaxFloat64
+		for subjectID, centroid := range centroids {
+			if d := cosineDistance(vec, centroid); d < bestDist {
+				bestDist = d
+				bestSubjectID = subjectID
+			}
+		}
+		if bestSubjectID != 0 && bestDist <= clusterThreshold {
+			if err := idx.subjectMgr.AssignFace(f.ID, bestSubjectID); err != nil {
+				return err
+			}
+			continue
+		}
+		unmatched = append(unmatched, f)
+	}
+
+	type cluster struct {
+		centroid []float32
+		faces    []models.Face
+	}
+	var clusters []*cluster
+
+	for _, f := range unmatched {
+		vec := decodeEmbedding(f.Embedding)
+		var best *cluster
+		bestDist := math.MaxFloat64
+		for _, c := range clusters {
+			if d := cosineDistance(vec, c.centroid); d < bestDist {
+				bestDist = d
+				best = c
+			}
+		}
+		if best != nil && bestDist <= clusterThreshold {
+			best.faces = append(best.faces, f)
+			continue
+		}
+		clusters = append(clusters, &cluster{centroid: vec, faces: []models.Face{f}})
+	}
+
+	for _, c := range clusters {
+		if len(c.faces) < 2 {
+			continue
+		}
+		subject, err := idx.subjectMgr.CreateSubject("Unknown")
+		if err != nil {
+			return err
+		}
+		for _, f := range c.faces {
+			if err := idx.subjectMgr.AssignFace(f.ID, subject.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// subjectCentroids computes the average embedding of each subject's
+// assigned faces, keyed by subject ID.
+func (idx *Indexer) subjectCentroids() (map[int64][]float32, error) {
+	assigned, err := idx.subjectMgr.AssignedFaces()
+	if err != nil {
+		return nil, err
+	}
+
+	sums := make(map[int64][]float32)
+	counts := make(map[int64]int)
+	for _, f := range assigned {
+		if f.SubjectID == nil {
+			continue
+		}
+		subjectID := *f.SubjectID
+		vec := decodeEmbedding(f.Embedding)
+		sum, ok := sums[subjectID]
+		if !ok {
+			sum = make([]float32, len(vec))
+		}
+		for i, v := range vec {
+			sum[i] += v
+		}
+		sums[subjectID] = sum
+		counts[subjectID]++
+	}
+
+	centroids := make(map[int64][]float32, len(sums))
+	for subjectID, sum := range sums {
+		centroid := make([]float32, len(sum))
+		for i, v := range sum {
+			centroid[i] = v / float32(counts[subjectID])
+		}
+		centroids[subjectID] = centroid
+	}
+	return centroids, nil
+}
+
+func encodeEmbedding(v [128]float32) []byte {
+	buf := make([]byte, 128*4)
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeEmbedding(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return v
+}
+
+func cosineDistance(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 1
+	}
+	return 1 - dot/(math.Sqrt(na)*math.Sqrt(nb))
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"github.com/zenazn/goji/web"
+	"net/http"
+)
+
+func toggleFavorite(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	photo, err := photoMgr.Get(c.URLParams["id"])
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if photo == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if !photo.CanEdit(currentUser) {
+		handleForbidden(w)
+		return
+	}
+	if err := photoMgr.ToggleFavorite(photo, currentUser); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, photo, http.StatusOK)
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"fmt"
+	"github.com/danjac/photoshare/api/form"
+	"github.com/danjac/photoshare/api/models"
 	"github.com/gorilla/feeds"
 	"github.com/zenazn/goji/web"
 	"net/http"
@@ -47,7 +49,7 @@ func photoFeed(c web.C,
 
 func latestFeed(c web.C, w http.ResponseWriter, r *http.Request) {
 
-	photos, err := photoMgr.All(1, "")
+	photos, err := photoMgr.Photos(form.PhotoSearch{Count: models.PageSize})
 
 	if err != nil {
 		handleServerError(w, err)
@@ -59,7 +61,7 @@ func latestFeed(c web.C, w http.ResponseWriter, r *http.Request) {
 
 func popularFeed(c web.C, w http.ResponseWriter, r *http.Request) {
 
-	photos, err := photoMgr.All(1, "votes")
+	photos, err := photoMgr.Photos(form.PhotoSearch{Count: models.PageSize, OrderBy: "votes"})
 
 	if err != nil {
 		handleServerError(w, err)
@@ -69,6 +71,35 @@ func popularFeed(c web.C, w http.ResponseWriter, r *http.Request) {
 	photoFeed(c, w, r, "Popular photos", "Most upvoted photos", "/popular", photos)
 }
 
+func favoritesFeed(c web.C, w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(c.URLParams["userID"], 10, 0)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	owner, exists, err := userMgr.GetActive(userID)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+
+	photos, err := photoMgr.Favorites(1, userID)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+
+	title := "Favorites of " + owner.Name
+	description := "Favorite photos of " + owner.Name
+	link := fmt.Sprintf("/favorites/%d", userID)
+
+	photoFeed(c, w, r, title, description, link, photos)
+}
+
 func ownerFeed(c web.C, w http.ResponseWriter, r *http.Request) {
 	ownerID, err := strconv.ParseInt(c.URLParams["ownerID"], 10, 0)
 	if err != nil {
@@ -89,7 +120,7 @@ func ownerFeed(c web.C, w http.ResponseWriter, r *http.Request) {
 	description := "List of feeds for " + owner.Name
 	link := fmt.Sprintf("/owner/%d/%s", ownerID, owner.Name)
 
-	photos, err := photoMgr.ByOwnerID(1, ownerID)
+	photos, err := photoMgr.Photos(form.PhotoSearch{Owner: owner.Name, Count: models.PageSize})
 
 	if err != nil {
 		handleServerError(w, err)
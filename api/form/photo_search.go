@@ -0,0 +1,102 @@
+// Package form parses and validates HTTP request input into typed values
+// the rest of the application can work with directly.
+package form
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCount = 12
+	maxCount     = 100
+	dateLayout   = "2006-01-02"
+)
+
+// PhotoSearch is the parsed representation of a photo search query, for
+// example: "tag:sunset owner:jane before:2015-01-01 votes>5 mountains".
+type PhotoSearch struct {
+	Q             string
+	Tag           string
+	Owner         string
+	Before        *time.Time
+	After         *time.Time
+	MinVotes      *int64
+	FavoritesOnly bool
+	OrderBy       string
+	Count         int64
+	Offset        int64
+}
+
+// ParsePhotoSearch builds a PhotoSearch from the query string of r. Recognised
+// filter tokens (tag:, owner:, before:, after:, votes>N) are stripped out of
+// the free-text query; anything left over becomes Q.
+func ParsePhotoSearch(r *http.Request) (*PhotoSearch, error) {
+
+	f := &PhotoSearch{
+		Count:         defaultCount,
+		FavoritesOnly: r.FormValue("favoritesOnly") == "true",
+		OrderBy:       r.FormValue("orderBy"),
+	}
+
+	var words []string
+
+	for _, word := range strings.Fields(r.FormValue("q")) {
+		switch {
+		case strings.HasPrefix(word, "tag:"):
+			f.Tag = strings.TrimPrefix(word, "tag:")
+		case strings.HasPrefix(word, "owner:"):
+			f.Owner = strings.TrimPrefix(word, "owner:")
+		case strings.HasPrefix(word, "before:"):
+			t, err := time.Parse(dateLayout, strings.TrimPrefix(word, "before:"))
+			if err != nil {
+				return nil, err
+			}
+			f.Before = &t
+		case strings.HasPrefix(word, "after:"):
+			t, err := time.Parse(dateLayout, strings.TrimPrefix(word, "after:"))
+			if err != nil {
+				return nil, err
+			}
+			f.After = &t
+		case strings.HasPrefix(word, "votes>"):
+			n, err := strconv.ParseInt(strings.TrimPrefix(word, "votes>"), 10, 0)
+			if err != nil {
+				return nil, err
+			}
+			f.MinVotes = &n
+		default:
+			words = append(words, word)
+		}
+	}
+
+	f.Q = strings.Join(words, " ")
+
+	if count := r.FormValue("count"); count != "" {
+		n, err := strconv.ParseInt(count, 10, 0)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 && n <= maxCount {
+			f.Count = n
+		}
+	}
+
+	if offset := r.FormValue("offset"); offset != "" {
+		n, err := strconv.ParseInt(offset, 10, 0)
+		if err != nil {
+			return nil, err
+		}
+		f.Offset = n
+	}
+
+	return f, nil
+}
+
+// IsEmpty reports whether the search carries no filters or free-text query.
+func (f *PhotoSearch) IsEmpty() bool {
+	return f.Q == "" && f.Tag == "" && f.Owner == "" &&
+		f.Before == nil && f.After == nil && f.MinVotes == nil && !f.FavoritesOnly
+}
@@ -0,0 +1,270 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"github.com/coopernurse/gorp"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+func generateSlug() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AlbumManager handles CRUD and sharing operations for albums.
+type AlbumManager interface {
+	Insert(*Album) error
+	Update(*Album) error
+	Delete(*Album) error
+	Get(int64) (*Album, error)
+	GetDetail(int64, *User) (*AlbumDetail, error)
+	ByOwnerID(int64, int64) (*AlbumList, error)
+	AddPhoto(album *Album, photoID int64) error
+	RemovePhoto(album *Album, photoID int64) error
+	Reorder(album *Album, photoIDs []int64) error
+	CreateLink(album *Album, password string, expiresAt time.Time, maxViews int64) (*Link, error)
+	GetLinkBySlug(slug string) (*Link, error)
+	RegisterView(link *Link) error
+}
+
+// Album groups a set of photos under a single title, owned by a user.
+type Album struct {
+	ID        int64     `db:"id" json:"id"`
+	OwnerID   int64     `db:"owner_id" json:"ownerId"`
+	Title     string    `db:"title" json:"title"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+// AlbumPhoto is the join row between an album and a photo, tracking display order.
+type AlbumPhoto struct {
+	AlbumID  int64 `db:"album_id" json:"albumId"`
+	PhotoID  int64 `db:"photo_id" json:"photoId"`
+	Position int64 `db:"position" json:"position"`
+}
+
+// AlbumList is a page of albums.
+type AlbumList struct {
+	Albums      []Album `json:"albums"`
+	Total       int64   `json:"total"`
+	CurrentPage int64   `json:"currentPage"`
+	NumPages    int64   `json:"numPages"`
+}
+
+// AlbumDetail is an album together with its ordered photos and the viewer's permissions.
+type AlbumDetail struct {
+	Album       `db:"-"`
+	Photos      []Photo      `db:"-" json:"photos"`
+	Permissions *Permissions `db:"-" json:"perms"`
+}
+
+// Link is a published, optionally password-protected, view of an album.
+type Link struct {
+	ID           int64      `db:"id" json:"id"`
+	AlbumID      int64      `db:"album_id" json:"albumId"`
+	Slug         string     `db:"slug" json:"slug"`
+	PasswordHash string     `db:"password_hash" json:"-"`
+	ExpiresAt    *time.Time `db:"expires_at" json:"expiresAt"`
+	MaxViews     int64      `db:"max_views" json:"maxViews"`
+	NumViews     int64      `db:"num_views" json:"numViews"`
+	CreatedAt    time.Time  `db:"created_at" json:"createdAt"`
+}
+
+// HasPassword reports whether visitors must supply a password to view the link.
+func (link *Link) HasPassword() bool {
+	return link.PasswordHash != ""
+}
+
+// CheckPassword compares the given password against the stored bcrypt hash.
+func (link *Link) CheckPassword(password string) bool {
+	if !link.HasPassword() {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) == nil
+}
+
+// Expired reports whether the link has passed its expiry timestamp or view limit.
+func (link *Link) Expired() bool {
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(time.Now()) {
+		return true
+	}
+	if link.MaxViews > 0 && link.NumViews >= link.MaxViews {
+		return true
+	}
+	return false
+}
+
+func (album *Album) PreInsert(s gorp.SqlExecutor) error {
+	album.CreatedAt = time.Now()
+	return nil
+}
+
+func (album *Album) CanEdit(user *User) bool {
+	if user == nil || !user.IsAuthenticated {
+		return false
+	}
+	return user.IsAdmin || album.OwnerID == user.ID
+}
+
+func (album *Album) CanDelete(user *User) bool {
+	return album.CanEdit(user)
+}
+
+type defaultAlbumManager struct{}
+
+var albumMgr = &defaultAlbumManager{}
+
+// NewAlbumManager returns the default AlbumManager implementation.
+func NewAlbumManager() AlbumManager {
+	return albumMgr
+}
+
+func (mgr *defaultAlbumManager) Insert(album *Album) error {
+	return dbMap.Insert(album)
+}
+
+func (mgr *defaultAlbumManager) Update(album *Album) error {
+	_, err := dbMap.Update(album)
+	return err
+}
+
+func (mgr *defaultAlbumManager) Delete(album *Album) error {
+	t, err := dbMap.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := t.Exec("DELETE FROM album_photos WHERE album_id=$1", album.ID); err != nil {
+		t.Rollback()
+		return err
+	}
+	if _, err := t.Exec("DELETE FROM links WHERE album_id=$1", album.ID); err != nil {
+		t.Rollback()
+		return err
+	}
+	if _, err := t.Delete(album); err != nil {
+		t.Rollback()
+		return err
+	}
+	return t.Commit()
+}
+
+func (mgr *defaultAlbumManager) Get(albumID int64) (*Album, error) {
+	album := &Album{}
+	obj, err := dbMap.Get(album, albumID)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, nil
+	}
+	return obj.(*Album), nil
+}
+
+func (mgr *defaultAlbumManager) GetDetail(albumID int64, user *User) (*AlbumDetail, error) {
+	album := &AlbumDetail{}
+	if err := dbMap.SelectOne(&album.Album, "SELECT * FROM albums WHERE id=$1", albumID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if _, err := dbMap.Select(&album.Photos,
+		"SELECT p.* FROM photos p JOIN album_photos ap ON ap.photo_id = p.id "+
+			"WHERE ap.album_id=$1 ORDER BY ap.position ASC", albumID); err != nil {
+		return nil, err
+	}
+	album.Permissions = &Permissions{
+		Edit:   album.CanEdit(user),
+		Delete: album.CanDelete(user),
+	}
+	return album, nil
+}
+
+func (mgr *defaultAlbumManager) ByOwnerID(pageNum int64, ownerID int64) (*AlbumList, error) {
+	var (
+		albums []Album
+		total  int64
+		err    error
+	)
+	if total, err = dbMap.SelectInt("SELECT COUNT(id) FROM albums WHERE owner_id=$1", ownerID); err != nil {
+		return nil, err
+	}
+	if _, err = dbMap.Select(&albums,
+		"SELECT * FROM albums WHERE owner_id=$1 ORDER BY created_at DESC LIMIT $2 OFFSET $3",
+		ownerID, PageSize, getOffset(pageNum)); err != nil {
+		return nil, err
+	}
+	numPages := (total + PageSize - 1) / PageSize
+	return &AlbumList{Albums: albums, Total: total, CurrentPage: pageNum, NumPages: numPages}, nil
+}
+
+func (mgr *defaultAlbumManager) AddPhoto(album *Album, photoID int64) error {
+	position, err := dbMap.SelectInt("SELECT COUNT(photo_id) FROM album_photos WHERE album_id=$1", album.ID)
+	if err != nil {
+		return err
+	}
+	return dbMap.Insert(&AlbumPhoto{AlbumID: album.ID, PhotoID: photoID, Position: position})
+}
+
+func (mgr *defaultAlbumManager) RemovePhoto(album *Album, photoID int64) error {
+	_, err := dbMap.Exec("DELETE FROM album_photos WHERE album_id=$1 AND photo_id=$2", album.ID, photoID)
+	return err
+}
+
+func (mgr *defaultAlbumManager) Reorder(album *Album, photoIDs []int64) error {
+	t, err := dbMap.Begin()
+	if err != nil {
+		return err
+	}
+	for position, photoID := range photoIDs {
+		if _, err := t.Exec(
+			"UPDATE album_photos SET position=$1 WHERE album_id=$2 AND photo_id=$3",
+			position, album.ID, photoID); err != nil {
+			t.Rollback()
+			return err
+		}
+	}
+	return t.Commit()
+}
+
+func (mgr *defaultAlbumManager) CreateLink(album *Album, password string, expiresAt time.Time, maxViews int64) (*Link, error) {
+	link := &Link{
+		AlbumID:   album.ID,
+		Slug:      generateSlug(),
+		CreatedAt: time.Now(),
+	}
+	if !expiresAt.IsZero() {
+		link.ExpiresAt = &expiresAt
+	}
+	link.MaxViews = maxViews
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		link.PasswordHash = string(hash)
+	}
+	if err := dbMap.Insert(link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+func (mgr *defaultAlbumManager) GetLinkBySlug(slug string) (*Link, error) {
+	link := &Link{}
+	if err := dbMap.SelectOne(link, "SELECT * FROM links WHERE slug=$1", slug); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return link, nil
+}
+
+func (mgr *defaultAlbumManager) RegisterView(link *Link) error {
+	_, err := dbMap.Exec("UPDATE links SET num_views = num_views + 1 WHERE id=$1", link.ID)
+	return err
+}
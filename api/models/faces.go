@@ -0,0 +1,169 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// OnPhotoInserted, if set, is called after a photo is successfully inserted.
+// It lets other subsystems (such as the face indexer) react to new photos
+// without this package depending on them.
+var OnPhotoInserted func(photoID int64)
+
+// Face is a detected face region within a photo, with its embedding vector
+// used for clustering into Subjects.
+type Face struct {
+	ID        int64  `db:"id" json:"id"`
+	PhotoID   int64  `db:"photo_id" json:"photoId"`
+	X         int64  `db:"x" json:"x"`
+	Y         int64  `db:"y" json:"y"`
+	W         int64  `db:"w" json:"w"`
+	H         int64  `db:"h" json:"h"`
+	Embedding []byte `db:"embedding" json:"-"`
+	SubjectID *int64 `db:"subject_id" json:"subjectId"`
+}
+
+// Subject is a person, identified across photos by their clustered faces.
+type Subject struct {
+	ID           int64  `db:"id" json:"id"`
+	Name         string `db:"name" json:"name"`
+	CoverPhotoID *int64 `db:"cover_photo_id" json:"coverPhotoId"`
+}
+
+// SubjectManager manages detected faces and the subjects (people) they are
+// clustered into.
+type SubjectManager interface {
+	Get(int64) (*Subject, error)
+	All() ([]Subject, error)
+	Update(*Subject) error
+	Merge(into *Subject, from *Subject) error
+	PhotosBySubject(subjectID, pageNum int64) (*PhotoList, error)
+	InsertFace(face *Face) error
+	FacesForPhoto(photoID int64) ([]Face, error)
+	UnassignedFaces(limit int) ([]Face, error)
+	AssignedFaces() ([]Face, error)
+	AssignFace(faceID int64, subjectID int64) error
+	CreateSubject(name string) (*Subject, error)
+}
+
+type defaultSubjectManager struct{}
+
+var subjectMgr = &defaultSubjectManager{}
+
+// NewSubjectManager returns the default SubjectManager implementation.
+func NewSubjectManager() SubjectManager {
+	return subjectMgr
+}
+
+func (mgr *defaultSubjectManager) Get(subjectID int64) (*Subject, error) {
+	subject := &Subject{}
+	obj, err := dbMap.Get(subject, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, nil
+	}
+	return obj.(*Subject), nil
+}
+
+func (mgr *defaultSubjectManager) All() ([]Subject, error) {
+	var subjects []Subject
+	if _, err := dbMap.Select(&subjects, "SELECT * FROM subjects ORDER BY name ASC"); err != nil {
+		return nil, err
+	}
+	return subjects, nil
+}
+
+func (mgr *defaultSubjectManager) Update(subject *Subject) error {
+	_, err := dbMap.Update(subject)
+	return err
+}
+
+func (mgr *defaultSubjectManager) CreateSubject(name string) (*Subject, error) {
+	subject := &Subject{Name: name}
+	if err := dbMap.Insert(subject); err != nil {
+		return nil, err
+	}
+	return subject, nil
+}
+
+// Merge reassigns every face belonging to from onto into, then deletes from.
+func (mgr *defaultSubjectManager) Merge(into *Subject, from *Subject) error {
+	t, err := dbMap.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := t.Exec("UPDATE faces SET subject_id=$1 WHERE subject_id=$2", into.ID, from.ID); err != nil {
+		t.Rollback()
+		return err
+	}
+	if _, err := t.Delete(from); err != nil {
+		t.Rollback()
+		return err
+	}
+	return t.Commit()
+}
+
+func (mgr *defaultSubjectManager) PhotosBySubject(subjectID, pageNum int64) (*PhotoList, error) {
+	var (
+		photos []Photo
+		total  int64
+		err    error
+	)
+	if total, err = dbMap.SelectInt(
+		"SELECT COUNT(DISTINCT p.id) FROM photos p "+
+			"JOIN faces f ON f.photo_id = p.id WHERE f.subject_id=$1", subjectID); err != nil {
+		return nil, err
+	}
+	if _, err = dbMap.Select(&photos,
+		"SELECT DISTINCT p.* FROM photos p JOIN faces f ON f.photo_id = p.id "+
+			"WHERE f.subject_id=$1 ORDER BY p.created_at DESC LIMIT $2 OFFSET $3",
+		subjectID, PageSize, getOffset(pageNum)); err != nil {
+		return nil, err
+	}
+	return NewPhotoList(photos, total, PageSize, getOffset(pageNum)), nil
+}
+
+func (mgr *defaultSubjectManager) InsertFace(face *Face) error {
+	return dbMap.Insert(face)
+}
+
+func (mgr *defaultSubjectManager) FacesForPhoto(photoID int64) ([]Face, error) {
+	var faces []Face
+	if _, err := dbMap.Select(&faces, "SELECT * FROM faces WHERE photo_id=$1", photoID); err != nil {
+		return nil, err
+	}
+	return faces, nil
+}
+
+func (mgr *defaultSubjectManager) UnassignedFaces(limit int) ([]Face, error) {
+	var faces []Face
+	if _, err := dbMap.Select(&faces,
+		"SELECT * FROM faces WHERE subject_id IS NULL ORDER BY id LIMIT $1", limit); err != nil {
+		return nil, err
+	}
+	return faces, nil
+}
+
+// AssignedFaces returns every face already assigned to a subject, for
+// building per-subject centroids when matching newly-detected faces.
+func (mgr *defaultSubjectManager) AssignedFaces() ([]Face, error) {
+	var faces []Face
+	if _, err := dbMap.Select(&faces, "SELECT * FROM faces WHERE subject_id IS NOT NULL"); err != nil {
+		return nil, err
+	}
+	return faces, nil
+}
+
+func (mgr *defaultSubjectManager) AssignFace(faceID int64, subjectID int64) error {
+	face := &Face{}
+	obj, err := dbMap.Get(face, faceID)
+	if err != nil {
+		return err
+	}
+	if obj == nil {
+		return sql.ErrNoRows
+	}
+	_, err = dbMap.Exec("UPDATE faces SET subject_id=$1 WHERE id=$2", subjectID, faceID)
+	return err
+}
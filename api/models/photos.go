@@ -4,8 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/coopernurse/gorp"
+	"github.com/danjac/photoshare/api/form"
 	"github.com/danjac/photoshare/api/storage"
-	"math"
+	"github.com/lib/pq"
 	"strings"
 	"time"
 )
@@ -19,27 +20,28 @@ type PhotoManager interface {
 	Get(string) (*Photo, error)
 	GetDetail(string, *User) (*PhotoDetail, error)
 	GetTagCounts() ([]TagCount, error)
-	All(int64, string) (*PhotoList, error)
-	ByOwnerID(int64, string) (*PhotoList, error)
-	Search(int64, string) (*PhotoList, error)
+	Photos(form.PhotoSearch) (*PhotoList, error)
 	UpdateTags(*Photo) error
+	ToggleFavorite(photo *Photo, user *User) error
+	Favorites(int64, int64) (*PhotoList, error)
+	GetMany(ids []int64) ([]Photo, error)
+	BatchDelete(ids []int64, user *User) error
+	BatchTag(ids []int64, tags []string, user *User) error
 }
 
 type PhotoList struct {
-	Photos      []Photo `json:"photos"`
-	Total       int64   `json:"total"`
-	CurrentPage int64   `json:"currentPage"`
-	NumPages    int64   `json:"numPages"`
+	Photos []Photo `json:"photos"`
+	Total  int64   `json:"total"`
+	Count  int64   `json:"count"`
+	Offset int64   `json:"offset"`
 }
 
-func NewPhotoList(photos []Photo, total int64, page int64) *PhotoList {
-	numPages := int64(math.Ceil(float64(total) / float64(PageSize)))
-
+func NewPhotoList(photos []Photo, total, count, offset int64) *PhotoList {
 	return &PhotoList{
-		Photos:      photos,
-		Total:       total,
-		CurrentPage: page,
-		NumPages:    numPages,
+		Photos: photos,
+		Total:  total,
+		Count:  count,
+		Offset: offset,
 	}
 }
 
@@ -63,6 +65,18 @@ type Photo struct {
 	Tags      []string  `db:"-" json:"tags"`
 	UpVotes   int64     `db:"up_votes" json:"upVotes"`
 	DownVotes int64     `db:"down_votes" json:"downVotes"`
+	Favorite  bool      `db:"favorite" json:"favorite"`
+
+	TakenAt  *time.Time `db:"taken_at" json:"takenAt"`
+	Camera   string     `db:"camera" json:"camera"`
+	Lens     string     `db:"lens" json:"lens"`
+	ISO      string     `db:"iso" json:"iso"`
+	Shutter  string     `db:"shutter" json:"shutter"`
+	Aperture string     `db:"aperture" json:"aperture"`
+	Lat      *float64   `db:"lat" json:"lat"`
+	Lng      *float64   `db:"lng" json:"lng"`
+
+	RawFilename string `db:"raw_filename" json:"rawFilename"`
 }
 
 var photoCleaner = storage.NewPhotoCleaner()
@@ -148,10 +162,23 @@ func (mgr *defaultPhotoManager) Insert(photo *Photo) error {
 	if err := mgr.UpdateTags(photo); err != nil {
 		return err
 	}
-	return t.Commit()
+	if err := t.Commit(); err != nil {
+		return err
+	}
+	if OnPhotoInserted != nil {
+		OnPhotoInserted(photo.ID)
+	}
+	return nil
 }
 
 func (mgr *defaultPhotoManager) UpdateTags(photo *Photo) error {
+	return updateTags(dbMap, photo)
+}
+
+// updateTags writes photo.Tags using exec, so callers that need the write
+// to participate in a transaction (e.g. BatchTag) can pass a gorp.Transaction
+// instead of the package-level dbMap.
+func updateTags(exec gorp.SqlExecutor, photo *Photo) error {
 
 	var (
 		args    = []string{"$1"}
@@ -167,10 +194,10 @@ func (mgr *defaultPhotoManager) UpdateTags(photo *Photo) error {
 		}
 	}
 	if isEmpty && photo.ID != 0 {
-		_, err := dbMap.Exec("DELETE FROM photo_tags WHERE photo_id=$1", photo.ID)
+		_, err := exec.Exec("DELETE FROM photo_tags WHERE photo_id=$1", photo.ID)
 		return err
 	}
-	_, err := dbMap.Exec(fmt.Sprintf("SELECT add_tags(%s)", strings.Join(args, ",")), params...)
+	_, err := exec.Exec(fmt.Sprintf("SELECT add_tags(%s)", strings.Join(args, ",")), params...)
 	return err
 
 }
@@ -223,103 +250,204 @@ func (mgr *defaultPhotoManager) GetDetail(photoID string, user *User) (*PhotoDet
 
 }
 
-func (mgr *defaultPhotoManager) ByOwnerID(pageNum int64, ownerID string) (*PhotoList, error) {
+// Photos runs a parameterized, composable photo query built from f. It
+// replaces the former All/ByOwnerID/Search methods, all of which were just
+// different slices of the same underlying query.
+func (mgr *defaultPhotoManager) Photos(f form.PhotoSearch) (*PhotoList, error) {
 
 	var (
-		photos []Photo
-		err    error
-		total  int64
+		joins   []string
+		clauses []string
+		params  []interface{}
+		photos  []Photo
 	)
 
-	if total, err = dbMap.SelectInt("SELECT COUNT(id) FROM photos WHERE owner_id=$1", ownerID); err != nil {
-		return nil, err
+	bind := func(v interface{}) string {
+		params = append(params, v)
+		return fmt.Sprintf("$%d", len(params))
 	}
 
-	if _, err = dbMap.Select(&photos,
-		"SELECT * FROM photos WHERE owner_id = $1"+
-			"ORDER BY (up_votes - down_votes) DESC, created_at DESC LIMIT $2 OFFSET $3",
-		ownerID, PageSize, getOffset(pageNum)); err != nil {
-		return nil, err
+	joins = append(joins, "INNER JOIN users u ON u.id = p.owner_id")
+
+	if f.Tag != "" {
+		joins = append(joins, "INNER JOIN photo_tags pt ON pt.photo_id = p.id",
+			"INNER JOIN tags t ON t.id = pt.tag_id")
+		clauses = append(clauses, "t.name = "+bind(strings.ToLower(f.Tag)))
 	}
-	return NewPhotoList(photos, total, pageNum), nil
-}
 
-func (mgr *defaultPhotoManager) Search(pageNum int64, q string) (*PhotoList, error) {
+	if f.Owner != "" {
+		clauses = append(clauses, "UPPER(u.name::text) = UPPER("+bind(f.Owner)+")")
+	}
 
-	var (
-		clauses []string
-		params  []interface{}
-		err     error
-		photos  []Photo
-		total   int64
-	)
+	if f.Before != nil {
+		clauses = append(clauses, "p.created_at < "+bind(*f.Before))
+	}
 
-	if q == "" {
-		return nil, nil
+	if f.After != nil {
+		clauses = append(clauses, "p.created_at > "+bind(*f.After))
 	}
 
-	for num, word := range strings.Split(q, " ") {
-		word = strings.TrimSpace(word)
-		if word == "" || num > 6 {
-			break
-		}
-		word = "%" + word + "%"
-		num += 1
-		clauses = append(clauses, fmt.Sprintf(
-			"SELECT DISTINCT p.* FROM photos p "+
-				"INNER JOIN users u ON u.id = p.owner_id  "+
-				"LEFT JOIN photo_tags pt ON pt.photo_id = p.id "+
-				"LEFT JOIN tags t ON pt.tag_id=t.id "+
-				"WHERE UPPER(p.title::text) LIKE UPPER($%d) OR UPPER(u.name::text) LIKE UPPER($%d) OR t.name LIKE $%d",
-			num, num, num))
+	if f.MinVotes != nil {
+		clauses = append(clauses, "(p.up_votes - p.down_votes) > "+bind(*f.MinVotes))
+	}
 
-		params = append(params, interface{}(word))
+	if f.FavoritesOnly {
+		clauses = append(clauses, "p.favorite = true")
 	}
 
-	clausesSql := strings.Join(clauses, " INTERSECT ")
+	if f.Q != "" {
+		clauses = append(clauses, "UPPER(p.title::text) LIKE UPPER("+bind("%"+f.Q+"%")+")")
+	}
 
-	countSql := fmt.Sprintf("SELECT COUNT(id) FROM (%s) q", clausesSql)
-	if total, err = dbMap.SelectInt(countSql, params...); err != nil {
-		return nil, err
+	from := "FROM photos p " + strings.Join(joins, " ")
+
+	where := ""
+	if len(clauses) > 0 {
+		where = " WHERE " + strings.Join(clauses, " AND ")
 	}
 
-	numParams := len(params)
+	countSql := "SELECT COUNT(DISTINCT p.id) " + from + where
+	total, err := dbMap.SelectInt(countSql, params...)
+	if err != nil {
+		return nil, err
+	}
 
-	sql := fmt.Sprintf("SELECT * FROM (%s) q ORDER BY (up_votes - down_votes) DESC, created_at DESC LIMIT $%d OFFSET $%d",
-		clausesSql, numParams+1, numParams+2)
+	orderBy := "p.created_at"
+	if f.OrderBy == "votes" {
+		orderBy = "(p.up_votes - p.down_votes)"
+	}
 
-	params = append(params, interface{}(PageSize))
-	params = append(params, interface{}(getOffset(pageNum)))
+	selectSql := "SELECT DISTINCT p.* " + from + where +
+		" ORDER BY " + orderBy + " DESC LIMIT " + bind(f.Count) + " OFFSET " + bind(f.Offset)
 
-	if _, err = dbMap.Select(&photos, sql, params...); err != nil {
+	if _, err := dbMap.Select(&photos, selectSql, params...); err != nil {
 		return nil, err
 	}
-	return NewPhotoList(photos, total, pageNum), nil
+
+	return NewPhotoList(photos, total, f.Count, f.Offset), nil
+}
+
+// ToggleFavorite flips the favorite flag on photo, if user is allowed to edit it.
+func (mgr *defaultPhotoManager) ToggleFavorite(photo *Photo, user *User) error {
+	if !photo.CanEdit(user) {
+		return nil
+	}
+	photo.Favorite = !photo.Favorite
+	_, err := dbMap.Exec("UPDATE photos SET favorite=$1 WHERE id=$2", photo.Favorite, photo.ID)
+	return err
 }
 
-func (mgr *defaultPhotoManager) All(pageNum int64, orderBy string) (*PhotoList, error) {
+// Favorites returns the favorited photos owned by ownerID.
+func (mgr *defaultPhotoManager) Favorites(pageNum int64, ownerID int64) (*PhotoList, error) {
 
 	var (
 		total  int64
 		photos []Photo
 		err    error
 	)
-	if orderBy == "votes" {
-		orderBy = "(up_votes - down_votes)"
-	} else {
-		orderBy = "created_at"
-	}
 
-	if total, err = dbMap.SelectInt("SELECT COUNT(id) FROM photos"); err != nil {
+	if total, err = dbMap.SelectInt(
+		"SELECT COUNT(id) FROM photos WHERE owner_id=$1 AND favorite = true", ownerID); err != nil {
 		return nil, err
 	}
 
 	if _, err = dbMap.Select(&photos,
-		"SELECT * FROM photos "+
-			"ORDER BY "+orderBy+" DESC LIMIT $1 OFFSET $2", PageSize, getOffset(pageNum)); err != nil {
+		"SELECT * FROM photos WHERE owner_id=$1 AND favorite = true "+
+			"ORDER BY created_at DESC LIMIT $2 OFFSET $3", ownerID, PageSize, getOffset(pageNum)); err != nil {
+		return nil, err
+	}
+	return NewPhotoList(photos, total, PageSize, getOffset(pageNum)), nil
+}
+
+// GetMany fetches the photos matching ids, in no particular order, with
+// each photo's tags populated.
+func (mgr *defaultPhotoManager) GetMany(ids []int64) ([]Photo, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var photos []Photo
+	if _, err := dbMap.Select(&photos, "SELECT * FROM photos WHERE id = ANY($1)", pq.Array(ids)); err != nil {
 		return nil, err
 	}
-	return NewPhotoList(photos, total, pageNum), nil
+	if err := loadTags(photos); err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// loadTags populates the Tags field of each photo in place.
+func loadTags(photos []Photo) error {
+	if len(photos) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(photos))
+	for i, photo := range photos {
+		ids[i] = photo.ID
+	}
+	var rows []struct {
+		PhotoID int64  `db:"photo_id"`
+		Name    string `db:"name"`
+	}
+	if _, err := dbMap.Select(&rows,
+		"SELECT pt.photo_id, t.name FROM tags t JOIN photo_tags pt ON pt.tag_id=t.id "+
+			"WHERE pt.photo_id = ANY($1)", pq.Array(ids)); err != nil {
+		return err
+	}
+	tagsByPhotoID := make(map[int64][]string)
+	for _, row := range rows {
+		tagsByPhotoID[row.PhotoID] = append(tagsByPhotoID[row.PhotoID], row.Name)
+	}
+	for i, photo := range photos {
+		photos[i].Tags = tagsByPhotoID[photo.ID]
+	}
+	return nil
+}
+
+// BatchDelete removes all of the given photos in a single transaction,
+// skipping (and not erroring on) any the user isn't allowed to delete.
+func (mgr *defaultPhotoManager) BatchDelete(ids []int64, user *User) error {
+	photos, err := mgr.GetMany(ids)
+	if err != nil {
+		return err
+	}
+	t, err := dbMap.Begin()
+	if err != nil {
+		return err
+	}
+	for _, photo := range photos {
+		if !photo.CanDelete(user) {
+			continue
+		}
+		if _, err := t.Delete(&photo); err != nil {
+			t.Rollback()
+			return err
+		}
+	}
+	return t.Commit()
+}
+
+// BatchTag adds tags to all of the given photos in a single transaction,
+// skipping (and not erroring on) any the user isn't allowed to edit.
+func (mgr *defaultPhotoManager) BatchTag(ids []int64, tags []string, user *User) error {
+	photos, err := mgr.GetMany(ids)
+	if err != nil {
+		return err
+	}
+	t, err := dbMap.Begin()
+	if err != nil {
+		return err
+	}
+	for _, photo := range photos {
+		if !photo.CanEdit(user) {
+			continue
+		}
+		photo.Tags = append(photo.Tags, tags...)
+		if err := updateTags(t, &photo); err != nil {
+			t.Rollback()
+			return err
+		}
+	}
+	return t.Commit()
 }
 
 func (mgr *defaultPhotoManager) GetTagCounts() ([]TagCount, error) {
@@ -8,16 +8,30 @@ import (
 )
 
 var (
-	mailer       = email.NewMailer()
-	photoMgr     = models.NewPhotoManager()
-	userMgr      = models.NewUserManager()
-	ownerUrl     = regexp.MustCompile(`/api/photos/owner/(?P<ownerID>\d+)$`)
-	photoUrl     = regexp.MustCompile(`/api/photos/(?P<id>\d+)$`)
-	titleUrl     = regexp.MustCompile(`/api/photos/(?P<id>\d+)/title$`)
-	tagsUrl      = regexp.MustCompile(`/api/photos/(?P<id>\d+)/tags$`)
-	downvoteUrl  = regexp.MustCompile(`/api/photos/(?P<id>\d+)/downvote$`)
-	upvoteUrl    = regexp.MustCompile(`/api/photos/(?P<id>\d+)/upvote$`)
-	ownerFeedUrl = regexp.MustCompile(`/feeds/owner/(?P<ownerID>\d+)$`)
+	mailer           = email.NewMailer()
+	photoMgr         = models.NewPhotoManager()
+	userMgr          = models.NewUserManager()
+	albumMgr         = models.NewAlbumManager()
+	ownerUrl         = regexp.MustCompile(`/api/photos/owner/(?P<ownerID>\d+)$`)
+	photoUrl         = regexp.MustCompile(`/api/photos/(?P<id>\d+)$`)
+	titleUrl         = regexp.MustCompile(`/api/photos/(?P<id>\d+)/title$`)
+	tagsUrl          = regexp.MustCompile(`/api/photos/(?P<id>\d+)/tags$`)
+	downvoteUrl      = regexp.MustCompile(`/api/photos/(?P<id>\d+)/downvote$`)
+	upvoteUrl        = regexp.MustCompile(`/api/photos/(?P<id>\d+)/upvote$`)
+	ownerFeedUrl     = regexp.MustCompile(`/feeds/owner/(?P<ownerID>\d+)$`)
+	albumUrl         = regexp.MustCompile(`/api/albums/(?P<id>\d+)$`)
+	albumPhotosUrl   = regexp.MustCompile(`/api/albums/(?P<id>\d+)/photos$`)
+	albumPhotoUrl    = regexp.MustCompile(`/api/albums/(?P<id>\d+)/photos/(?P<photoID>\d+)$`)
+	albumReorderUrl  = regexp.MustCompile(`/api/albums/(?P<id>\d+)/photos/order$`)
+	albumLinksUrl    = regexp.MustCompile(`/api/albums/(?P<id>\d+)/links$`)
+	albumZipUrl      = regexp.MustCompile(`/api/albums/(?P<id>\d+)\.zip$`)
+	sharedAlbumUrl   = regexp.MustCompile(`/s/(?P<slug>[^/]+)$`)
+	favoriteUrl      = regexp.MustCompile(`/api/photos/(?P<id>\d+)/favorite$`)
+	favoritesFeedUrl = regexp.MustCompile(`/feeds/favorites/(?P<userID>\d+)$`)
+	exifUrl          = regexp.MustCompile(`/api/photos/(?P<id>\d+)/exif$`)
+	subjectUrl       = regexp.MustCompile(`/api/subjects/(?P<id>\d+)$`)
+	subjectPhotosUrl = regexp.MustCompile(`/api/subjects/(?P<id>\d+)/photos$`)
+	faceSubjectUrl   = regexp.MustCompile(`/api/faces/(?P<id>\d+)/subject$`)
 )
 
 func init() {
@@ -28,11 +42,13 @@ func init() {
 	goji.Get(ownerUrl, photosByOwnerID)
 	goji.Get(photoUrl, photoDetail)
 	goji.Delete(photoUrl, deletePhoto)
+	goji.Get(exifUrl, getPhotoExif)
 
 	goji.Patch(titleUrl, editPhotoTitle)
 	goji.Patch(tagsUrl, editPhotoTags)
 	goji.Patch(downvoteUrl, voteDown)
 	goji.Patch(upvoteUrl, voteUp)
+	goji.Patch(favoriteUrl, toggleFavorite)
 
 	goji.Get("/api/auth/", authenticate)
 	goji.Post("/api/auth/", login)
@@ -46,6 +62,27 @@ func init() {
 	goji.Get("/feeds/", latestFeed)
 	goji.Get("/feeds/popular/", popularFeed)
 	goji.Get(ownerFeedUrl, ownerFeed)
+	goji.Get(favoritesFeedUrl, favoritesFeed)
 
 	goji.Handle("/api/messages/*", messageHandler)
+
+	goji.Post("/api/photos/batch/delete", batchDeletePhotos)
+	goji.Post("/api/photos/batch/tag", batchTagPhotos)
+	goji.Post("/api/photos/batch/download", batchDownloadPhotos)
+
+	goji.Get("/api/albums/", getAlbums)
+	goji.Post("/api/albums/", createAlbum)
+	goji.Put(albumUrl, updateAlbum)
+	goji.Delete(albumUrl, deleteAlbum)
+	goji.Post(albumPhotosUrl, addPhotoToAlbum)
+	goji.Delete(albumPhotoUrl, removePhotoFromAlbum)
+	goji.Put(albumReorderUrl, reorderAlbumPhotos)
+	goji.Post(albumLinksUrl, createAlbumLink)
+	goji.Get(albumZipUrl, downloadAlbumZip)
+	goji.Get(sharedAlbumUrl, viewSharedAlbum)
+
+	goji.Get("/api/subjects/", getSubjects)
+	goji.Patch(subjectUrl, updateSubject)
+	goji.Get(subjectPhotosUrl, subjectPhotos)
+	goji.Patch(faceSubjectUrl, assignFaceSubject)
 }
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"github.com/danjac/photoshare/api/form"
+	"github.com/zenazn/goji/web"
+	"net/http"
+	"strconv"
+)
+
+// writePhotoList writes the photos result envelope as JSON, along with
+// X-Result-Count and X-Result-Offset headers describing the page returned.
+func writePhotoList(w http.ResponseWriter, photos *PhotoList) {
+	w.Header().Set("X-Result-Count", strconv.FormatInt(photos.Count, 10))
+	w.Header().Set("X-Result-Offset", strconv.FormatInt(photos.Offset, 10))
+	writeJSON(w, photos, http.StatusOK)
+}
+
+func getPhotos(c web.C, w http.ResponseWriter, r *http.Request) {
+	f, err := form.ParsePhotoSearch(r)
+	if err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	photos, err := photoMgr.Photos(*f)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writePhotoList(w, photos)
+}
+
+func searchPhotos(c web.C, w http.ResponseWriter, r *http.Request) {
+	f, err := form.ParsePhotoSearch(r)
+	if err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	if f.IsEmpty() {
+		http.Error(w, "a search query or filter is required", http.StatusBadRequest)
+		return
+	}
+	photos, err := photoMgr.Photos(*f)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writePhotoList(w, photos)
+}
+
+func photosByOwnerID(c web.C, w http.ResponseWriter, r *http.Request) {
+	ownerID, err := strconv.ParseInt(c.URLParams["ownerID"], 10, 0)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	owner, exists, err := userMgr.GetActive(ownerID)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := form.ParsePhotoSearch(r)
+	if err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	f.Owner = owner.Name
+	photos, err := photoMgr.Photos(*f)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writePhotoList(w, photos)
+}
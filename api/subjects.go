@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/danjac/photoshare/api/models"
+	"github.com/zenazn/goji/web"
+	"net/http"
+	"strconv"
+)
+
+var faceSubjectMgr = models.NewSubjectManager()
+
+type subjectForm struct {
+	Name        string `json:"name"`
+	MergeFromID int64  `json:"mergeFromId"`
+}
+
+type assignFaceForm struct {
+	SubjectID int64 `json:"subjectId"`
+}
+
+func getSubjects(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if !currentUser.IsAuthenticated {
+		handleForbidden(w)
+		return
+	}
+	subjects, err := faceSubjectMgr.All()
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, subjects, http.StatusOK)
+}
+
+// updateSubject renames or merges a subject. Renaming and merging People
+// records is an admin-only action: it touches face assignments that may
+// span photos the caller doesn't own.
+func updateSubject(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if !currentUser.IsAdmin {
+		handleForbidden(w)
+		return
+	}
+	subjectID, err := strconv.ParseInt(c.URLParams["id"], 10, 0)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	subject, err := faceSubjectMgr.Get(subjectID)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if subject == nil {
+		http.NotFound(w, r)
+		return
+	}
+	form := &subjectForm{}
+	if err := json.NewDecoder(r.Body).Decode(form); err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	if form.MergeFromID != 0 {
+		from, err := faceSubjectMgr.Get(form.MergeFromID)
+		if err != nil {
+			handleServerError(w, err)
+			return
+		}
+		if from == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if err := faceSubjectMgr.Merge(subject, from); err != nil {
+			handleServerError(w, err)
+			return
+		}
+	}
+	if form.Name != "" {
+		subject.Name = form.Name
+	}
+	if err := faceSubjectMgr.Update(subject); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, subject, http.StatusOK)
+}
+
+func subjectPhotos(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if !currentUser.IsAuthenticated {
+		handleForbidden(w)
+		return
+	}
+	subjectID, err := strconv.ParseInt(c.URLParams["id"], 10, 0)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	pageNum, _ := strconv.ParseInt(r.FormValue("page"), 10, 0)
+	if pageNum == 0 {
+		pageNum = 1
+	}
+	photos, err := faceSubjectMgr.PhotosBySubject(subjectID, pageNum)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, photos, http.StatusOK)
+}
+
+func assignFaceSubject(c web.C, w http.ResponseWriter, r *http.Request) {
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		handleServerError(w, err)
+		return
+	}
+	if !currentUser.IsAdmin {
+		handleForbidden(w)
+		return
+	}
+	faceID, err := strconv.ParseInt(c.URLParams["id"], 10, 0)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	form := &assignFaceForm{}
+	if err := json.NewDecoder(r.Body).Decode(form); err != nil {
+		handleBadRequest(w, err)
+		return
+	}
+	if err := faceSubjectMgr.AssignFace(faceID, form.SubjectID); err != nil {
+		handleServerError(w, err)
+		return
+	}
+	writeJSON(w, true, http.StatusOK)
+}
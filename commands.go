@@ -1,9 +1,15 @@
 package photoshare
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/codegangsta/negroni"
+	"github.com/danjac/photoshare/api/convert"
+	"github.com/danjac/photoshare/api/faces"
+	"github.com/danjac/photoshare/api/models"
 	"io/ioutil"
 	"log"
 	"os"
@@ -12,6 +18,25 @@ import (
 	"strings"
 )
 
+var rawConverter = convert.New()
+
+const facesModelsDir = "./faces-models"
+
+// facesReclusterDelta is how many newly-detected faces accumulate before
+// the indexer reclusters. TODO: move to config once the face indexer has
+// its own section there.
+const facesReclusterDelta = 10
+
+func startFaceIndexer() {
+	detector, err := faces.NewDetector(facesModelsDir)
+	if err != nil {
+		log.Printf("faces: detector unavailable, face indexing disabled: %v", err)
+	}
+	indexer := faces.NewIndexer(detector, models.NewSubjectManager(), models.NewPhotoManager(), facesReclusterDelta)
+	models.OnPhotoInserted = indexer.Enqueue
+	go indexer.Run(nil)
+}
+
 // Serve runs the HTTP server
 func Serve() {
 
@@ -21,6 +46,8 @@ func Serve() {
 	}
 	defer cfg.close()
 
+	startFaceIndexer()
+
 	runtime.GOMAXPROCS((runtime.NumCPU() * 2) + 1)
 
 	n := negroni.Classic()
@@ -36,6 +63,24 @@ func storeFile(cfg *config,
 	tags []string,
 	userID int64) error {
 	log.Println(title)
+
+	var rawFilename string
+	ext := strings.ToLower(path.Ext(filename))
+
+	if convert.Recognized(ext) {
+		if !cfg.RawEnabled || rawConverter == nil {
+			log.Printf("skipping RAW/HEIF file %s: conversion not enabled or no converter available", filename)
+			return nil
+		}
+		derivative, err := rawConverter.Convert(context.Background(), filename, ext)
+		if err != nil {
+			return err
+		}
+		rawFilename = filename
+		filename = derivative
+		contentType = "image/jpeg"
+	}
+
 	file, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -46,16 +91,51 @@ func storeFile(cfg *config,
 	if err != nil {
 		return err
 	}
+
+	if rawFilename != "" {
+		if rawFile, err := os.Open(rawFilename); err == nil {
+			defer rawFile.Close()
+			if err := cfg.filestore.store(rawFile, rawFilename, "application/octet-stream"); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+
+	exifData := ExtractExif(filename)
+	if rawFilename != "" {
+		// The XMP sidecar, if any, sits next to the original RAW/HEIF file,
+		// not the converted derivative we just ran EXIF extraction against.
+		exifData.Keywords = readXMPKeywords(rawFilename + ".xmp")
+	}
+	if len(exifData.Keywords) > 0 {
+		tags = append(tags, exifData.Keywords...)
+	}
+
 	photo := &photo{
-		Title:    title,
-		Filename: name,
-		Tags:     tags,
-		OwnerID:  userID,
+		Title:       title,
+		Filename:    name,
+		RawFilename: rawFilename,
+		Tags:        tags,
+		OwnerID:     userID,
+		TakenAt:     exifData.TakenAt,
+		Camera:      exifData.Camera,
+		Lens:        exifData.Lens,
+		ISO:         exifData.ISO,
+		Shutter:     exifData.Shutter,
+		Aperture:    exifData.Aperture,
+		Lat:         exifData.Lat,
+		Lng:         exifData.Lng,
 	}
 	if err := cfg.datamapper.createPhoto(photo); err != nil {
 		return err
 	}
 
+	if sidecar, err := json.Marshal(exifData); err == nil {
+		if err := cfg.filestore.store(bytes.NewReader(sidecar), name+".json", "application/json"); err != nil {
+			log.Println(err)
+		}
+	}
+
 	return nil
 }
 
@@ -71,15 +151,18 @@ func scanDir(cfg *config, userID int64, baseDir, dirname string) {
 			filename := path.Join(dirname, info.Name())
 			tags := strings.Split(strings.TrimSpace(dirname[len(baseDir):]), "/")
 			ext := strings.ToLower(path.Ext(info.Name()))
-			if ext != ".jpg" && ext != ".png" {
+			if ext != ".jpg" && ext != ".png" && !convert.Recognized(ext) {
 				continue
 			}
-			title := info.Name()[:len(info.Name())-4]
+			title := info.Name()[:len(info.Name())-len(ext)]
 			var contentType string
-			if ext == ".jpg" {
+			switch {
+			case ext == ".jpg":
 				contentType = "image/jpeg"
-			} else {
+			case ext == ".png":
 				contentType = "image/png"
+			default:
+				contentType = "application/octet-stream"
 			}
 			if err := storeFile(cfg, filename, title, contentType, tags, userID); err != nil {
 				log.Println(err)
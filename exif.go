@@ -0,0 +1,122 @@
+package photoshare
+
+import (
+	"encoding/xml"
+	"github.com/rwcarlsen/goexif/exif"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ExifData holds the subset of EXIF/XMP metadata we extract from an
+// uploaded or imported photo and persist alongside it.
+type ExifData struct {
+	TakenAt  *time.Time `json:"takenAt,omitempty"`
+	Camera   string     `json:"camera,omitempty"`
+	Lens     string     `json:"lens,omitempty"`
+	ISO      string     `json:"iso,omitempty"`
+	Shutter  string     `json:"shutter,omitempty"`
+	Aperture string     `json:"aperture,omitempty"`
+	Lat      *float64   `json:"lat,omitempty"`
+	Lng      *float64   `json:"lng,omitempty"`
+	Keywords []string   `json:"keywords,omitempty"`
+}
+
+// ExtractExif reads EXIF tags from filename, and XMP keywords from an
+// optional sidecar file named filename + ".xmp" (e.g. "a.jpg.xmp"). Missing
+// or unreadable metadata is not an error - photos without EXIF data are
+// common and should still be importable. It is called by storeFile (the
+// CLI Import path); the web upload handler does not call it.
+func ExtractExif(filename string) *ExifData {
+
+	data := &ExifData{}
+
+	if file, err := os.Open(filename); err == nil {
+		defer file.Close()
+		if x, err := exif.Decode(file); err == nil {
+			if t, err := x.DateTime(); err == nil {
+				data.TakenAt = &t
+			}
+			if tag, err := x.Get(exif.Model); err == nil {
+				data.Camera, _ = tag.StringVal()
+			}
+			if tag, err := x.Get(exif.LensModel); err == nil {
+				data.Lens, _ = tag.StringVal()
+			}
+			if tag, err := x.Get(exif.ISOSpeedRatings); err == nil {
+				data.ISO = tag.String()
+			}
+			if tag, err := x.Get(exif.ExposureTime); err == nil {
+				data.Shutter = tag.String()
+			}
+			if tag, err := x.Get(exif.FNumber); err == nil {
+				data.Aperture = tag.String()
+			}
+			if lat, lng, err := x.LatLong(); err == nil {
+				data.Lat = &lat
+				data.Lng = &lng
+			}
+		}
+	}
+
+	data.Keywords = readXMPKeywords(filename + ".xmp")
+
+	return data
+}
+
+// readXMPKeywords extracts the dc:subject bag entries from an XMP sidecar
+// file, if one exists next to the photo. Other rdf:Bag/rdf:Seq properties
+// in the same packet (dc:creator, hierarchical subjects, etc.) are ignored.
+func readXMPKeywords(xmpFilename string) []string {
+	file, err := os.Open(xmpFilename)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var (
+		keywords  []string
+		inSubject bool
+		inBag     bool
+		inLi      bool
+	)
+
+	decoder := xml.NewDecoder(file)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return keywords
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "subject":
+				inSubject = true
+			case inSubject && t.Name.Local == "Bag":
+				inBag = true
+			case inSubject && inBag && t.Name.Local == "li":
+				inLi = true
+			}
+		case xml.EndElement:
+			switch {
+			case t.Name.Local == "subject":
+				inSubject = false
+			case t.Name.Local == "Bag":
+				inBag = false
+			case t.Name.Local == "li":
+				inLi = false
+			}
+		case xml.CharData:
+			if inSubject && inBag && inLi {
+				if keyword := strings.TrimSpace(string(t)); keyword != "" {
+					keywords = append(keywords, keyword)
+				}
+			}
+		}
+	}
+	return keywords
+}